@@ -23,13 +23,14 @@ type node struct {
 
 	b []byte // The blice at this node
 
+	blen int32 // len(b), kept separately so the length of a matched
+	// blice is cheap to recover from a node reconstructed by
+	// fromTables, which does not retain the original bytes
+
 	output bool // True means this node represents a blice that should
 	// be output when matching
 	index int // index into original dictionary if output is true
 
-	counter int // Set to the value of the Matcher.counter when a
-	// match is output to prevent duplicate output
-
 	// The use of fixed size arrays is space-inefficient but fast for
 	// lookups.
 
@@ -52,14 +53,19 @@ type node struct {
 }
 
 // Matcher is returned by NewMatcher and contains a list of blices to
-// match against
+// match against. Once built, a *Matcher is immutable and safe for
+// concurrent use by multiple goroutines: dedup state for Match lives
+// in a Scanner, not in the Matcher or its nodes, so one shared
+// dictionary can be matched against from many request handlers at
+// once without locking.
 type Matcher struct {
-	counter int // Counts the number of matches done, and is used to
-	// prevent output of multiple matches of the same string
-	root   *node // Points to trie[0]
+	root *node // Points to trie[0]
 
 	table [][]*node
 	tableSize int32
+
+	order    []int32 // State ids in breadth-first (trie depth) order, root first
+	patterns int     // Number of blices in the original dictionary
 }
 
 func (m *Matcher) tableGet(i int32) *node {
@@ -148,6 +154,7 @@ func (m *Matcher) buildTrie(dictionary [][]byte) {
 
 				c.b = make([]byte, len(path))
 				copy(c.b, path)
+				c.blen = int32(len(path))
 
 				// Nodes directly under the root node will have the
 				// root as their fail point as there are no suffixes
@@ -170,16 +177,21 @@ func (m *Matcher) buildTrie(dictionary [][]byte) {
 		n.index = i
 	}
 
+	m.patterns = len(dictionary)
+
 	l := new(list.List)
 	l.PushBack(m.root)
+	m.order = append(m.order, 1)
 
 	for l.Len() > 0 {
 		n := l.Remove(l.Front()).(*node)
 
 		for i := 0; i < 256; i++ {
-			c := m.tableGet(n.getChild(i))
+			id := n.getChild(i)
+			c := m.tableGet(id)
 			if c != nil {
 				l.PushBack(c)
+				m.order = append(m.order, id)
 
 				for j := 1; j < len(c.b); j++ {
 					c.fail = m.findBlice(c.b[j:])
@@ -241,12 +253,96 @@ func NewStringMatcher(dictionary []string) *Matcher {
 	return m
 }
 
+// Hit describes a single occurrence of a dictionary entry found by
+// FindAll. Pos is the offset of the last byte of the match (so the
+// match spans in[Pos-Len+1 : Pos+1]) and Len is the length in bytes of
+// the matched blice.
+type Hit struct {
+	Index int // index into the original dictionary
+	Pos   int // offset of the last matched byte
+	Len   int // length of the matched blice
+}
+
+// FindAll searches in for every occurrence of every blice in the
+// dictionary, including overlapping matches and repeated occurrences
+// of the same blice, and reports each one as a Hit. Unlike Match it
+// does not deduplicate by dictionary index: walking the full suffix
+// chain at each state means a Hit is emitted for every match found,
+// however many times it occurs.
+func (m *Matcher) FindAll(in []byte) []Hit {
+	var hits []Hit
+
+	n := m.root
+
+	for i, b := range in {
+		c := int(b)
+
+		if !n.root && m.tableGet(n.getChild(c)) == nil {
+			n = m.tableGet(n.getFails(c))
+		}
+
+		f := m.tableGet(n.getChild(c))
+		if f != nil {
+			n = f
+
+			if f.output {
+				hits = append(hits, Hit{Index: f.index, Pos: i, Len: int(f.blen)})
+			}
+
+			for !m.tableGet(f.suffix).root {
+				f = m.tableGet(f.suffix)
+				hits = append(hits, Hit{Index: f.index, Pos: i, Len: int(f.blen)})
+			}
+		}
+	}
+
+	return hits
+}
+
 // Match searches in for blices and returns all the blices found as
-// indexes into the original dictionary
+// indexes into the original dictionary. It is a convenience for
+// callers who only need the set of unique dictionary entries matched,
+// not their positions (FindAll reports those); it allocates a
+// throwaway Scanner for the call, so callers making many Match calls
+// (e.g. per-request in a server) should keep their own Scanner via
+// NewScanner instead.
 func (m *Matcher) Match(in []byte) []int {
-	m.counter += 1
+	return m.NewScanner().Match(in)
+}
+
+// Scanner holds the dedup state for repeated calls to Match against a
+// shared Matcher. A Matcher itself is immutable once built and safe
+// for concurrent use, but deduplicating a single Match call used to
+// require mutating per-node state on the Matcher, which made sharing
+// one Matcher across goroutines unsafe. A Scanner moves that state out
+// of the Matcher: each goroutine (e.g. each request handler) should
+// keep its own Scanner.
+type Scanner struct {
+	m *Matcher
+
+	// seen[index] holds the call number that dictionary entry index
+	// was last reported in, so a generation counter rather than a
+	// fresh map is enough to dedup within a single Match call.
+	seen []int
+	call int
+}
+
+// NewScanner returns a Scanner for matching against m. The Scanner
+// must not be used from more than one goroutine at a time, but
+// multiple Scanners may be used concurrently against the same m.
+func (m *Matcher) NewScanner() *Scanner {
+	return &Scanner{m: m, seen: make([]int, m.patterns)}
+}
+
+// Match searches in for blices and returns all the blices found as
+// indexes into the original dictionary, deduplicated within this call
+// only: an entry reported by an earlier call on the same Scanner is
+// reported again if it occurs in in.
+func (s *Scanner) Match(in []byte) []int {
+	s.call += 1
 	var hits []int
 
+	m := s.m
 	n := m.root
 
 	for _, b := range in {
@@ -260,16 +356,16 @@ func (m *Matcher) Match(in []byte) []int {
 		if f != nil {
 			n = f
 
-			if f.output && f.counter != m.counter {
+			if f.output && s.seen[f.index] != s.call {
 				hits = append(hits, f.index)
-				f.counter = m.counter
+				s.seen[f.index] = s.call
 			}
 
 			for !m.tableGet(f.suffix).root {
 				f = m.tableGet(f.suffix)
-				if f.counter != m.counter {
+				if s.seen[f.index] != s.call {
 					hits = append(hits, f.index)
-					f.counter = m.counter
+					s.seen[f.index] = s.call
 				} else {
 
 					// There's no point working our way up the
@@ -284,3 +380,66 @@ func (m *Matcher) Match(in []byte) []int {
 
 	return hits
 }
+
+// Count searches in for occurrences of every blice in the dictionary
+// and returns, for each dictionary index, the number of times that
+// blice occurs in in (including overlapping occurrences).
+//
+// Rather than walking the suffix chain for every input byte, Count
+// tallies a single visit per byte against the state reached and then
+// propagates those visit counts up the fail tree once: states are
+// processed deepest-first (the reverse of m.order, which buildTrie
+// builds breadth-first, so every state's fail target, being strictly
+// shallower, comes before it) so that visits[fail[v]] accumulates
+// visits[v] for every v. Since every node's output is a suffix of
+// every descendant reachable through fail links, the propagated total
+// at an output node equals the number of occurrences of that blice.
+// This makes Count O(len(in) + number of states + number of patterns),
+// rather than O(len(in) * depth) for a naive suffix-chain walk.
+//
+// Sharp edge: if the dictionary passed to NewMatcher/NewStringMatcher
+// contains the same blice more than once, buildTrie maps every
+// occurrence to the same trie node and that node only remembers the
+// last matching index (the same is true of Match and FindAll). For
+// Count this is worse than a merely missing result: the counted total
+// is attributed entirely to whichever duplicate index happened to be
+// inserted last, while every other duplicate index silently gets zero,
+// even though the blice did occur. Callers whose dictionaries may
+// contain duplicates should dedup before building the Matcher, or
+// treat duplicate indexes' counts as one shared total.
+func (m *Matcher) Count(in []byte) []int {
+	visits := make([]int, m.tableSize)
+
+	n := m.root
+	id := int32(1)
+
+	for _, b := range in {
+		c := int(b)
+
+		if !n.root && m.tableGet(n.getChild(c)) == nil {
+			id = n.getFails(c)
+			n = m.tableGet(id)
+		}
+
+		if cid := n.getChild(c); cid != 0 {
+			id = cid
+			n = m.tableGet(id)
+		}
+
+		visits[id] += 1
+	}
+
+	for i := len(m.order) - 1; i > 0; i-- {
+		v := m.order[i]
+		visits[m.tableGet(v).fail] += visits[v]
+	}
+
+	counts := make([]int, m.patterns)
+	for i := int32(1); i < m.tableSize; i++ {
+		if node := m.tableGet(i); node.output {
+			counts[node.index] += visits[i]
+		}
+	}
+
+	return counts
+}
@@ -0,0 +1,102 @@
+package ahocorasick
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Builder accumulates blices from multiple sources -- files, network,
+// config -- before building a Matcher, so callers don't need to
+// materialize a single [][]byte up front.
+type Builder struct {
+	patterns [][]byte
+}
+
+// NewBuilder returns an empty Builder.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// AddBytes adds a blice to the dictionary. The blice is copied, so the
+// caller may reuse p afterwards.
+func (b *Builder) AddBytes(p []byte) {
+	cp := make([]byte, len(p))
+	copy(cp, p)
+	b.patterns = append(b.patterns, cp)
+}
+
+// AddString adds a string to the dictionary.
+func (b *Builder) AddString(s string) {
+	b.AddBytes([]byte(s))
+}
+
+// LoadPatterns adds one blice per line read from the file at path. A
+// ".gz" suffix on path is treated as gzip-compressed input. Blank
+// lines are skipped.
+func (b *Builder) LoadPatterns(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			b.AddString(line)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Build returns a Matcher for the accumulated dictionary. The Builder
+// may go on to accumulate further blices for a later Build call; each
+// call returns an independent Matcher over whatever has been added so
+// far.
+func (b *Builder) Build() *Matcher {
+	dictionary := make([][]byte, len(b.patterns))
+	copy(dictionary, b.patterns)
+	return NewMatcher(dictionary)
+}
+
+// MatcherRef holds a *Matcher that can be atomically swapped out from
+// under concurrent readers, so a running server can rebuild its
+// automaton from an updated dictionary (e.g. on SIGHUP) and put the
+// new one into service without stopping traffic. Matchers themselves
+// are immutable once built, so readers that already loaded the old
+// *Matcher via Load may keep using it safely even after Replace.
+type MatcherRef struct {
+	p atomic.Pointer[Matcher]
+}
+
+// NewMatcherRef returns a MatcherRef holding m.
+func NewMatcherRef(m *Matcher) *MatcherRef {
+	r := &MatcherRef{}
+	r.p.Store(m)
+	return r
+}
+
+// Load returns the current Matcher.
+func (r *MatcherRef) Load() *Matcher {
+	return r.p.Load()
+}
+
+// Replace atomically swaps in m as the current Matcher.
+func (r *MatcherRef) Replace(m *Matcher) {
+	r.p.Store(m)
+}
@@ -0,0 +1,141 @@
+package ahocorasick
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestBuilderAddBytesAndAddString(t *testing.T) {
+	want := NewStringMatcher([]string{"she", "he", "her", "hers"})
+
+	b := NewBuilder()
+	b.AddString("she")
+	b.AddBytes([]byte("he"))
+	b.AddString("her")
+	b.AddBytes([]byte("hers"))
+	got := b.Build()
+
+	input := "ushers"
+	if !reflect.DeepEqual(got.Match([]byte(input)), want.Match([]byte(input))) {
+		t.Errorf("Match(%q) = %v, want %v", input, got.Match([]byte(input)), want.Match([]byte(input)))
+	}
+}
+
+func TestBuilderAddBytesCopiesInput(t *testing.T) {
+	b := NewBuilder()
+	p := []byte("foo")
+	b.AddBytes(p)
+
+	// The caller is free to mutate p after AddBytes returns.
+	p[0] = 'x'
+
+	m := b.Build()
+	if got := m.Match([]byte("foo")); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("Match(foo) = %v, want [0]; AddBytes must copy its input", got)
+	}
+}
+
+func TestBuilderBuildIsIndependentPerCall(t *testing.T) {
+	b := NewBuilder()
+	b.AddString("foo")
+	m1 := b.Build()
+
+	b.AddString("bar")
+	m2 := b.Build()
+
+	if got := m1.Match([]byte("foobar")); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("m1.Match(foobar) = %v, want [0]; earlier Build must not see later additions", got)
+	}
+	if got := m2.Match([]byte("foobar")); !reflect.DeepEqual(got, []int{0, 1}) {
+		t.Errorf("m2.Match(foobar) = %v, want [0 1]", got)
+	}
+}
+
+func TestBuilderLoadPatternsPlainFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "patterns.txt")
+	content := "she\nhe\n\nher\nhers\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	b := NewBuilder()
+	if err := b.LoadPatterns(path); err != nil {
+		t.Fatalf("LoadPatterns: %v", err)
+	}
+	m := b.Build()
+
+	want := NewStringMatcher([]string{"she", "he", "her", "hers"})
+	input := "ushers"
+	if got, w := m.Match([]byte(input)), want.Match([]byte(input)); !reflect.DeepEqual(got, w) {
+		t.Errorf("Match(%q) = %v, want %v", input, got, w)
+	}
+}
+
+func TestBuilderLoadPatternsGzipFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "patterns.txt.gz")
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	gz := gzip.NewWriter(f)
+	if _, err := gz.Write([]byte("she\nhe\nher\nhers\n")); err != nil {
+		t.Fatalf("gzip Write: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip Close: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	b := NewBuilder()
+	if err := b.LoadPatterns(path); err != nil {
+		t.Fatalf("LoadPatterns: %v", err)
+	}
+	m := b.Build()
+
+	want := NewStringMatcher([]string{"she", "he", "her", "hers"})
+	input := "ushers"
+	if got, w := m.Match([]byte(input)), want.Match([]byte(input)); !reflect.DeepEqual(got, w) {
+		t.Errorf("Match(%q) = %v, want %v", input, got, w)
+	}
+}
+
+func TestBuilderLoadPatternsMissingFile(t *testing.T) {
+	b := NewBuilder()
+	if err := b.LoadPatterns(filepath.Join(t.TempDir(), "does-not-exist.txt")); err == nil {
+		t.Fatal("LoadPatterns on a missing file returned nil error")
+	}
+}
+
+func TestMatcherRefLoadAndReplace(t *testing.T) {
+	m1 := NewStringMatcher([]string{"foo"})
+	m2 := NewStringMatcher([]string{"bar"})
+
+	r := NewMatcherRef(m1)
+	loaded := r.Load()
+	if loaded != m1 {
+		t.Fatalf("Load() = %p, want %p", loaded, m1)
+	}
+
+	r.Replace(m2)
+
+	// A *Matcher obtained before Replace must remain valid and keep
+	// returning results from the dictionary it was built with.
+	if got := loaded.Match([]byte("foo")); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("old Matcher.Match(foo) = %v, want [0]", got)
+	}
+
+	if got := r.Load(); got != m2 {
+		t.Errorf("Load() after Replace = %p, want %p", got, m2)
+	}
+	if got := r.Load().Match([]byte("bar")); !reflect.DeepEqual(got, []int{0}) {
+		t.Errorf("new Matcher.Match(bar) = %v, want [0]", got)
+	}
+}
@@ -0,0 +1,79 @@
+package ahocorasick
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCount(t *testing.T) {
+	cases := []struct {
+		name       string
+		dictionary []string
+		input      string
+		want       []int
+	}{
+		{
+			name:       "no occurrences",
+			dictionary: []string{"foo", "bar"},
+			input:      "quux",
+			want:       []int{0, 0},
+		},
+		{
+			name:       "each pattern occurs once, including via suffix links",
+			dictionary: []string{"she", "he", "her", "hers"},
+			input:      "ushers",
+			want:       []int{1, 1, 1, 1},
+		},
+		{
+			name:       "overlapping occurrences of the same blice",
+			dictionary: []string{"ab"},
+			input:      "ababab",
+			want:       []int{3},
+		},
+		{
+			name:       "nested patterns over a run of a single byte",
+			dictionary: []string{"a", "aa", "aaa"},
+			input:      "aaaa",
+			want:       []int{4, 3, 2},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := NewStringMatcher(c.dictionary)
+			got := m.Count([]byte(c.input))
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("Count(%q) = %v, want %v", c.input, got, c.want)
+			}
+		})
+	}
+}
+
+// TestCountMatchesFindAllTally checks Count's fail-tree propagation
+// against the slower, independently-implemented FindAll walk across a
+// range of inputs, rather than trusting either in isolation.
+func TestCountMatchesFindAllTally(t *testing.T) {
+	dictionary := []string{"he", "she", "his", "hers", "he", "h"}
+	m := NewStringMatcher(dictionary)
+
+	inputs := []string{
+		"",
+		"h",
+		"he",
+		"ushers",
+		"hishersheshe",
+		"aaaaaaaaaaaa",
+	}
+
+	for _, in := range inputs {
+		want := make([]int, m.patterns)
+		for _, hit := range m.FindAll([]byte(in)) {
+			want[hit.Index]++
+		}
+
+		got := m.Count([]byte(in))
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Count(%q) = %v, want %v (tallied from FindAll)", in, got, want)
+		}
+	}
+}
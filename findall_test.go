@@ -0,0 +1,78 @@
+package ahocorasick
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindAll(t *testing.T) {
+	cases := []struct {
+		name       string
+		dictionary []string
+		input      string
+		want       []Hit
+	}{
+		{
+			name:       "no match",
+			dictionary: []string{"foo"},
+			input:      "bar",
+			want:       nil,
+		},
+		{
+			name:       "single match reports ending offset and length",
+			dictionary: []string{"he"},
+			input:      "ahead",
+			want:       []Hit{{Index: 0, Pos: 2, Len: 2}},
+		},
+		{
+			name:       "overlapping suffix patterns all reported at the same position",
+			dictionary: []string{"she", "he", "her", "hers"},
+			input:      "ushers",
+			want: []Hit{
+				{Index: 0, Pos: 3, Len: 3}, // "she"
+				{Index: 1, Pos: 3, Len: 2}, // "he", a suffix of "she" ending at the same byte
+				{Index: 2, Pos: 4, Len: 3}, // "her"
+				{Index: 3, Pos: 5, Len: 4}, // "hers"
+			},
+		},
+		{
+			name:       "repeated occurrences of the same blice are all reported",
+			dictionary: []string{"ab"},
+			input:      "ababab",
+			want: []Hit{
+				{Index: 0, Pos: 1, Len: 2},
+				{Index: 0, Pos: 3, Len: 2},
+				{Index: 0, Pos: 5, Len: 2},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			m := NewStringMatcher(c.dictionary)
+			got := m.FindAll([]byte(c.input))
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("FindAll(%q) = %#v, want %#v", c.input, got, c.want)
+			}
+		})
+	}
+}
+
+func TestMatchDedupsByIndex(t *testing.T) {
+	m := NewStringMatcher([]string{"she", "he", "her", "hers"})
+
+	got := m.Match([]byte("ushers"))
+	want := []int{0, 1, 2, 3}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Match = %#v, want %#v", got, want)
+	}
+
+	// A repeated occurrence of the same blice must not produce a
+	// repeated entry in Match's result, unlike FindAll.
+	got = m.Match([]byte("hehehehe"))
+	want = []int{1}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Match(repeated) = %#v, want %#v", got, want)
+	}
+}
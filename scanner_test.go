@@ -0,0 +1,74 @@
+package ahocorasick
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestScannerDedupsPerCallNotAcrossCalls(t *testing.T) {
+	m := NewStringMatcher([]string{"he", "she"})
+	s := m.NewScanner()
+
+	got := s.Match([]byte("heshehe"))
+	want := []int{0, 1}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("first Match = %#v, want %#v", got, want)
+	}
+
+	// A second call on the same Scanner must see "he" again: dedup is
+	// per-call, not cumulative across the Scanner's lifetime.
+	got = s.Match([]byte("he"))
+	want = []int{0}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("second Match = %#v, want %#v", got, want)
+	}
+}
+
+// TestMatcherConcurrentUse exercises the concurrency guarantee
+// documented on Matcher: a single built *Matcher may be matched
+// against from many goroutines at once, each keeping its own Scanner
+// state. Run with -race to check for data races on the shared trie.
+func TestMatcherConcurrentUse(t *testing.T) {
+	m := NewStringMatcher([]string{"she", "he", "her", "hers"})
+	inputs := []string{"ushers", "hishers", "heshe", "nomatch", "hersheshe"}
+
+	want := make([][]int, len(inputs))
+	for i, in := range inputs {
+		want[i] = m.Match([]byte(in))
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s := m.NewScanner()
+			for round := 0; round < 50; round++ {
+				for i, in := range inputs {
+					got := s.Match([]byte(in))
+					if !reflect.DeepEqual(got, want[i]) {
+						t.Errorf("concurrent Match(%q) = %#v, want %#v", in, got, want[i])
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestMatcherMatchConvenienceWrapper(t *testing.T) {
+	m := NewStringMatcher([]string{"foo", "bar"})
+
+	// Matcher.Match must not share dedup state across independent
+	// calls, since each call allocates its own Scanner.
+	got1 := m.Match([]byte("foofoo"))
+	got2 := m.Match([]byte("barbar"))
+
+	if want := []int{0}; !reflect.DeepEqual(got1, want) {
+		t.Errorf("Match(foofoo) = %#v, want %#v", got1, want)
+	}
+	if want := []int{1}; !reflect.DeepEqual(got2, want) {
+		t.Errorf("Match(barbar) = %#v, want %#v", got2, want)
+	}
+}
@@ -0,0 +1,394 @@
+package ahocorasick
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// magic identifies the encoding produced by MarshalBinary/WriteTo so
+// UnmarshalBinary/ReadFrom can reject data written by an incompatible
+// version instead of silently misinterpreting it.
+var magic = [4]byte{'A', 'C', '0', '1'}
+
+// MaxTableSize bounds the tableSize that ReadFrom/UnmarshalBinary will
+// accept before allocating anything for it. Without this, a truncated
+// or corrupted encoding (or a bogus tableSize field) could make
+// ReadFrom try to allocate an arbitrary amount of memory before it
+// ever gets to check whether the rest of the data is actually there.
+// The default is deliberately modest -- gotoTable alone is
+// tableSize*256 int32s, so even this cap is a quarter of a gigabyte --
+// raise it if you intentionally load dictionaries with more states
+// than the default allows.
+var MaxTableSize int32 = 1 << 18 // 256K states
+
+// bytesPerState is the number of bytes tables() encodes per state:
+// 256 goto-table int32s, three more int32s (suffix, fail, index) and
+// one output byte. ReadFrom uses it to reject a tableSize the input
+// can't possibly back up with real data, before allocating anything.
+const bytesPerState = 256*4 + 3*4 + 1
+
+// MarshalBinary encodes the fully-built automaton -- the goto table,
+// fail links, suffix links, output bits and dictionary indexes -- into
+// a compact binary form. UnmarshalBinary reloads it without repeating
+// buildTrie, which is the expensive part for large dictionaries.
+func (m *Matcher) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := m.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary reloads a Matcher encoded by MarshalBinary. The
+// receiver is overwritten with the decoded automaton.
+func (m *Matcher) UnmarshalBinary(data []byte) error {
+	_, err := m.ReadFrom(bytes.NewReader(data))
+	return err
+}
+
+// WriteTo writes the fully-built automaton to w in the format used by
+// MarshalBinary. It implements io.WriterTo so a Matcher can be
+// written directly to a file without going through the intermediate
+// buffer MarshalBinary uses; each table is still encoded in memory
+// before it is written.
+func (m *Matcher) WriteTo(w io.Writer) (int64, error) {
+	cw := &countingWriter{w: w}
+
+	if _, err := cw.Write(magic[:]); err != nil {
+		return cw.n, err
+	}
+
+	if err := binary.Write(cw, binary.LittleEndian, m.tableSize); err != nil {
+		return cw.n, err
+	}
+	if err := binary.Write(cw, binary.LittleEndian, int32(m.patterns)); err != nil {
+		return cw.n, err
+	}
+
+	gotoTable, suffix, fail, output, index := m.tables()
+
+	for _, s := range [][]int32{gotoTable, suffix, fail, index} {
+		if err := binary.Write(cw, binary.LittleEndian, s); err != nil {
+			return cw.n, err
+		}
+	}
+	if err := binary.Write(cw, binary.LittleEndian, output); err != nil {
+		return cw.n, err
+	}
+
+	return cw.n, nil
+}
+
+// ReadFrom reads an automaton written by WriteTo, replacing the
+// receiver's contents. It implements io.ReaderFrom.
+func (m *Matcher) ReadFrom(r io.Reader) (int64, error) {
+	cr := &countingReader{r: r}
+
+	var got [4]byte
+	if _, err := io.ReadFull(cr, got[:]); err != nil {
+		return cr.n, err
+	}
+	if got != magic {
+		return cr.n, fmt.Errorf("ahocorasick: data is not a Matcher encoding")
+	}
+
+	var tableSize, patterns int32
+	if err := binary.Read(cr, binary.LittleEndian, &tableSize); err != nil {
+		return cr.n, err
+	}
+	if err := binary.Read(cr, binary.LittleEndian, &patterns); err != nil {
+		return cr.n, err
+	}
+
+	if tableSize < 2 || tableSize > MaxTableSize {
+		return cr.n, fmt.Errorf("ahocorasick: tableSize %d out of range (want 2..%d); refusing to allocate", tableSize, MaxTableSize)
+	}
+	if patterns < 0 || patterns > tableSize {
+		return cr.n, fmt.Errorf("ahocorasick: patterns %d out of range for tableSize %d", patterns, tableSize)
+	}
+
+	need := int64(tableSize) * bytesPerState
+	if remaining, ok := byteLen(r); ok && need > remaining {
+		return cr.n, fmt.Errorf("ahocorasick: tableSize %d needs %d bytes of table data but only %d remain; refusing to allocate", tableSize, need, remaining)
+	}
+
+	gotoTable := make([]int32, int64(tableSize)*256)
+	suffix := make([]int32, tableSize)
+	fail := make([]int32, tableSize)
+	index := make([]int32, tableSize)
+	output := make([]byte, tableSize)
+
+	for _, s := range [][]int32{gotoTable, suffix, fail, index} {
+		if err := binary.Read(cr, binary.LittleEndian, s); err != nil {
+			return cr.n, err
+		}
+	}
+	if err := binary.Read(cr, binary.LittleEndian, output); err != nil {
+		return cr.n, err
+	}
+
+	if err := m.fromTables(tableSize, int(patterns), gotoTable, suffix, fail, output, index); err != nil {
+		return cr.n, err
+	}
+
+	return cr.n, nil
+}
+
+// tables flattens the node-pointer trie built by buildTrie into the
+// parallel arrays used by the binary format: a goto table indexed by
+// state*256+byte, plus per-state suffix, fail, output and
+// dictionary-index arrays. The per-byte resolved-fail table each node
+// carries at runtime is not serialized -- it is cheap to recompute
+// from gotoTable and fail (see fromTables) and storing it would
+// roughly double the size of the encoding for no benefit.
+func (m *Matcher) tables() (gotoTable, suffix, fail []int32, output []byte, index []int32) {
+	n := int(m.tableSize)
+	gotoTable = make([]int32, n*256)
+	suffix = make([]int32, n)
+	fail = make([]int32, n)
+	output = make([]byte, n)
+	index = make([]int32, n)
+
+	for i := 0; i < n; i++ {
+		nd := m.tableGet(int32(i))
+		if nd == nil {
+			continue
+		}
+		for c := 0; c < 256; c++ {
+			gotoTable[i*256+c] = nd.getChild(c)
+		}
+		suffix[i] = nd.suffix
+		fail[i] = nd.fail
+		if nd.output {
+			output[i] = 1
+			index[i] = int32(nd.index)
+		}
+	}
+
+	return
+}
+
+// validateTables checks that every cross-reference in the parallel
+// arrays -- gotoTable and fail entries used as state ids, index
+// entries used as dictionary indexes -- actually lands inside the
+// table/dictionary they index into. fromTables itself has no other
+// reason to doubt them (that validation-by-construction is exactly
+// what buildTrie normally provides and fromTables skips), so a
+// corrupted or adversarial encoding would otherwise reach an
+// out-of-range slice index and panic instead of returning the error
+// ReadFrom's callers are expected to handle.
+func validateTables(tableSize int32, patterns int, gotoTable, suffix, fail []int32, output []byte, index []int32) error {
+	for i, v := range gotoTable {
+		if v < 0 || v >= tableSize {
+			return fmt.Errorf("ahocorasick: gotoTable[%d] = %d out of range for tableSize %d", i, v, tableSize)
+		}
+	}
+	for i, v := range suffix {
+		if v < 0 || v >= tableSize {
+			return fmt.Errorf("ahocorasick: suffix[%d] = %d out of range for tableSize %d", i, v, tableSize)
+		}
+	}
+	for i, v := range fail {
+		if v < 0 || v >= tableSize {
+			return fmt.Errorf("ahocorasick: fail[%d] = %d out of range for tableSize %d", i, v, tableSize)
+		}
+	}
+	for i, o := range output {
+		if o != 0 && (index[i] < 0 || int(index[i]) >= patterns) {
+			return fmt.Errorf("ahocorasick: index[%d] = %d out of range for %d patterns", i, index[i], patterns)
+		}
+	}
+	return nil
+}
+
+// fromTables rebuilds the node-pointer trie from the parallel arrays
+// produced by tables. This is the inverse of the expensive part of
+// buildTrie: no suffix-link search against the dictionary is needed,
+// since suffix and fail are already resolved in the serialized data.
+// Reconstructed nodes do not retain the original blice bytes, only
+// their length (see node.blen).
+//
+// Each node's per-byte resolved-fail table is recomputed rather than
+// read back from disk: m.order (built here by a breadth-first walk of
+// gotoTable from the root) visits states in increasing depth, and every
+// fail link points to a strictly shallower state, so fails[i][c] can be
+// derived in a single pass as "i itself,
+// if i is the root or already has a real child for c; otherwise
+// whatever fails[fail[i]][c] resolved to" -- the same fallback chain
+// buildTrie's own fails computation walks one byte at a time, just
+// read off an already-resolved, shallower node instead of re-walked
+// from scratch.
+func (m *Matcher) fromTables(tableSize int32, patterns int, gotoTable, suffix, fail []int32, output []byte, index []int32) error {
+	if err := validateTables(tableSize, patterns, gotoTable, suffix, fail, output, index); err != nil {
+		return err
+	}
+
+	m.table = [][]*node{}
+	m.tableSize = tableSize
+	m.patterns = patterns
+	m.tableSet(0, nil)
+
+	nodes := make([]*node, tableSize)
+	for i := int32(1); i < tableSize; i++ {
+		nd := &node{
+			root:   i == 1,
+			suffix: suffix[i],
+			fail:   fail[i],
+			output: output[i] != 0,
+			index:  int(index[i]),
+		}
+		nodes[i] = nd
+		m.tableSet(i, nd)
+	}
+	m.root = nodes[1]
+
+	for i := int32(1); i < tableSize; i++ {
+		nd := nodes[i]
+		base := int(i) * 256
+		for c := 0; c < 256; c++ {
+			if v := gotoTable[base+c]; v != 0 {
+				nd.setChild(c, v)
+			}
+		}
+	}
+
+	m.order = m.order[:0]
+	m.order = append(m.order, 1)
+	seen := make([]bool, tableSize)
+	seen[1] = true
+	queue := []int32{1}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		nd := nodes[id]
+		for c := 0; c < 256; c++ {
+			cid := nd.getChild(c)
+			if cid != 0 && !seen[cid] {
+				seen[cid] = true
+				nodes[cid].blen = nd.blen + 1
+				m.order = append(m.order, cid)
+				queue = append(queue, cid)
+			}
+		}
+	}
+
+	for _, id := range m.order {
+		nd := nodes[id]
+		for c := 0; c < 256; c++ {
+			j := id
+			if !nd.root && nd.getChild(c) == 0 {
+				j = nodes[nd.fail].getFails(c)
+			}
+			nd.setFails(c, j)
+		}
+	}
+
+	return nil
+}
+
+// NewFromTables builds a Matcher directly from the parallel arrays
+// produced by (*Matcher).tables, skipping buildTrie entirely. It is
+// exported for use by code generated by (*Matcher).Generate, which
+// always emits tables produced by tables() for some already-built
+// Matcher; a caller that hand-builds tables with out-of-range
+// cross-references instead gets a panic, the same way other
+// constructors in this package panic on a malformed argument rather
+// than returning an error. Untrusted input should go through
+// ReadFrom/UnmarshalBinary instead, which validate and return an
+// error.
+func NewFromTables(tableSize int32, patterns int, gotoTable, suffix, fail []int32, output []byte, index []int32) *Matcher {
+	m := new(Matcher)
+	if err := m.fromTables(tableSize, patterns, gotoTable, suffix, fail, output, index); err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Generate writes w a Go source file declaring varName as a *Matcher
+// built from this automaton's tables via NewFromTables, so a large
+// dictionary can be compiled into a binary instead of rebuilt with
+// buildTrie on every process start.
+func (m *Matcher) Generate(w io.Writer, pkg, varName string) error {
+	gotoTable, suffix, fail, output, index := m.tables()
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "// Code generated by (*ahocorasick.Matcher).Generate; DO NOT EDIT.\n\n")
+	fmt.Fprintf(bw, "package %s\n\n", pkg)
+	fmt.Fprintf(bw, "import %q\n\n", "github.com/justecorruptio/ahocorasick")
+	fmt.Fprintf(bw, "var %s = ahocorasick.NewFromTables(\n", varName)
+	fmt.Fprintf(bw, "\t%d,\n", m.tableSize)
+	fmt.Fprintf(bw, "\t%d,\n", m.patterns)
+	writeInt32Slice(bw, gotoTable)
+	writeInt32Slice(bw, suffix)
+	writeInt32Slice(bw, fail)
+	writeByteSlice(bw, output)
+	writeInt32Slice(bw, index)
+	fmt.Fprintf(bw, ")\n")
+
+	return bw.Flush()
+}
+
+func writeInt32Slice(w *bufio.Writer, s []int32) {
+	fmt.Fprintf(w, "\t[]int32{")
+	for i, v := range s {
+		if i > 0 {
+			fmt.Fprint(w, ", ")
+		}
+		fmt.Fprintf(w, "%d", v)
+	}
+	fmt.Fprintf(w, "},\n")
+}
+
+func writeByteSlice(w *bufio.Writer, s []byte) {
+	fmt.Fprintf(w, "\t[]byte{")
+	for i, v := range s {
+		if i > 0 {
+			fmt.Fprint(w, ", ")
+		}
+		fmt.Fprintf(w, "%d", v)
+	}
+	fmt.Fprintf(w, "},\n")
+}
+
+// countingWriter wraps an io.Writer and tracks the number of bytes
+// written so WriteTo can report it as required by io.WriterTo.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// byteLen reports how many bytes are left to read from r and whether
+// that could be determined at all. *bytes.Reader and *strings.Reader
+// (what UnmarshalBinary and most in-memory callers pass) both support
+// this; a bare network or pipe io.Reader does not, in which case
+// ReadFrom falls back to just the tableSize cap.
+func byteLen(r io.Reader) (int64, bool) {
+	l, ok := r.(interface{ Len() int })
+	if !ok {
+		return 0, false
+	}
+	return int64(l.Len()), true
+}
+
+// countingReader wraps an io.Reader and tracks the number of bytes
+// read so ReadFrom can report it as required by io.ReaderFrom.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,122 @@
+package ahocorasick
+
+import (
+	"bytes"
+	"encoding/binary"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"testing"
+)
+
+func TestMarshalUnmarshalBinaryRoundTrip(t *testing.T) {
+	dictionary := []string{"she", "he", "her", "hers", "his"}
+	m := NewStringMatcher(dictionary)
+
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	var loaded Matcher
+	if err := loaded.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+
+	inputs := []string{"", "ushers", "hishersheshe", "nomatch"}
+	for _, in := range inputs {
+		wantHits := m.FindAll([]byte(in))
+		gotHits := loaded.FindAll([]byte(in))
+		if !reflect.DeepEqual(gotHits, wantHits) {
+			t.Errorf("FindAll(%q) after round-trip = %#v, want %#v", in, gotHits, wantHits)
+		}
+
+		wantCounts := m.Count([]byte(in))
+		gotCounts := loaded.Count([]byte(in))
+		if !reflect.DeepEqual(gotCounts, wantCounts) {
+			t.Errorf("Count(%q) after round-trip = %v, want %v", in, gotCounts, wantCounts)
+		}
+	}
+}
+
+func TestReadFromRejectsBogusTableSize(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(magic[:])
+	// A tableSize far beyond MaxTableSize: ReadFrom must reject this
+	// before allocating anything for it, rather than trying to honor
+	// it and running the process out of memory.
+	buf.Write([]byte{0xff, 0xff, 0xff, 0x7f}) // int32(0x7fffffff), little-endian
+	buf.Write([]byte{0, 0, 0, 0})             // patterns = 0
+
+	var m Matcher
+	if _, err := m.ReadFrom(&buf); err == nil {
+		t.Fatal("ReadFrom accepted an out-of-range tableSize instead of returning an error")
+	}
+}
+
+// TestReadFromRejectsTableSizeExceedingRemainingData hands ReadFrom a
+// *bytes.Buffer, which, like the *bytes.Reader UnmarshalBinary passes,
+// can report exactly how many bytes are left. A tableSize just inside
+// MaxTableSize but unsupported by the (tiny) remaining input must be
+// rejected with an error; the test checks only that an error is
+// returned, not at which point ReadFrom detects the shortfall.
+func TestReadFromRejectsTableSizeExceedingRemainingData(t *testing.T) {
+	var buf bytes.Buffer
+	buf.Write(magic[:])
+	binary.Write(&buf, binary.LittleEndian, MaxTableSize-1) // tableSize, just inside the cap
+	binary.Write(&buf, binary.LittleEndian, int32(0))       // patterns
+	buf.WriteByte(0)                                        // far short of the table data tableSize implies
+
+	var m Matcher
+	if _, err := m.ReadFrom(&buf); err == nil {
+		t.Fatal("ReadFrom accepted a tableSize the remaining input can't back up, instead of returning an error")
+	}
+}
+
+func TestReadFromRejectsOutOfRangeGotoTableEntry(t *testing.T) {
+	m := NewStringMatcher([]string{"she", "he", "her", "hers"})
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	// Corrupt the first gotoTable entry (right after the 12-byte
+	// magic+tableSize+patterns header) to a state id far beyond
+	// tableSize. fromTables must reject this instead of using it as a
+	// slice index and panicking.
+	corrupt := make([]byte, len(data))
+	copy(corrupt, data)
+	binary.LittleEndian.PutUint32(corrupt[12:16], 0x7fffffff)
+
+	var loaded Matcher
+	if err := loaded.UnmarshalBinary(corrupt); err == nil {
+		t.Fatal("UnmarshalBinary accepted an out-of-range gotoTable entry instead of returning an error")
+	}
+}
+
+func TestReadFromRejectsTruncatedData(t *testing.T) {
+	m := NewStringMatcher([]string{"she", "he", "her", "hers"})
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary: %v", err)
+	}
+
+	var loaded Matcher
+	if err := loaded.UnmarshalBinary(data[:len(data)/2]); err == nil {
+		t.Fatal("UnmarshalBinary accepted truncated data instead of returning an error")
+	}
+}
+
+func TestGenerateProducesValidGoSource(t *testing.T) {
+	m := NewStringMatcher([]string{"she", "he", "her", "hers"})
+
+	var buf bytes.Buffer
+	if err := m.Generate(&buf, "precompiled", "dict"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	fset := token.NewFileSet()
+	if _, err := parser.ParseFile(fset, "generated.go", buf.Bytes(), 0); err != nil {
+		t.Fatalf("Generate produced source that does not parse: %v", err)
+	}
+}
@@ -0,0 +1,134 @@
+package ahocorasick
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrStopped is returned by Stream.Write once OnHit has returned false,
+// and by any subsequent Write on the same Stream.
+var ErrStopped = errors.New("ahocorasick: scanning stopped by OnHit callback")
+
+// stepByte advances n by a single input byte at absolute offset pos,
+// invoking onHit for every output state reached by walking the suffix
+// chain. It returns the new state, and false if onHit asked to stop by
+// returning false, in which case scanning should not continue.
+func (m *Matcher) stepByte(n *node, b byte, pos int, onHit func(f *node, pos int) bool) (*node, bool) {
+	c := int(b)
+
+	if !n.root && m.tableGet(n.getChild(c)) == nil {
+		n = m.tableGet(n.getFails(c))
+	}
+
+	f := m.tableGet(n.getChild(c))
+	if f != nil {
+		n = f
+
+		if f.output && !onHit(f, pos) {
+			return n, false
+		}
+
+		for !m.tableGet(f.suffix).root {
+			f = m.tableGet(f.suffix)
+			if !onHit(f, pos) {
+				return n, false
+			}
+		}
+	}
+
+	return n, true
+}
+
+// Stream is an incremental Aho-Corasick scanner that keeps only the
+// current automaton state between calls, so it can scan a multi-GB
+// file or network stream without buffering the input. Create one with
+// Matcher.NewStream.
+type Stream struct {
+	m       *Matcher
+	n       *node
+	offset  int64
+	stopped bool
+
+	// OnHit is called for every match as bytes arrive, with the
+	// matched dictionary index and the absolute offset (within the
+	// whole stream, not just the current Write) of the last byte of
+	// the match. Returning false stops further scanning: the current
+	// and all future calls to Write return ErrStopped.
+	OnHit func(patternIndex, absoluteEndOffset int) bool
+}
+
+// NewStream returns a Stream that matches this Matcher's dictionary
+// against bytes written to it.
+func (m *Matcher) NewStream() *Stream {
+	return &Stream{m: m, n: m.root}
+}
+
+// Write implements io.Writer. It feeds p into the automaton, calling
+// OnHit for every match found, and always reports len(p) bytes
+// consumed unless OnHit stops scanning, in which case it returns the
+// number of bytes consumed up to and including the byte that triggered
+// the stop, along with ErrStopped.
+func (s *Stream) Write(p []byte) (int, error) {
+	if s.stopped {
+		return 0, ErrStopped
+	}
+
+	onHit := func(f *node, pos int) bool {
+		if s.OnHit == nil {
+			return true
+		}
+		return s.OnHit(f.index, pos)
+	}
+
+	for i, b := range p {
+		n, ok := s.m.stepByte(s.n, b, int(s.offset)+i, onHit)
+		s.n = n
+		if !ok {
+			s.stopped = true
+			s.offset += int64(i) + 1
+			return i + 1, ErrStopped
+		}
+	}
+
+	s.offset += int64(len(p))
+	return len(p), nil
+}
+
+// MatchReader scans r for every occurrence of every blice in the
+// dictionary, calling fn for each one as a Hit, without buffering the
+// whole input. Returning false from fn stops scanning and MatchReader
+// returns nil. It returns a non-nil error only if reading from r
+// fails.
+func (m *Matcher) MatchReader(r io.Reader, fn func(Hit) bool) error {
+	n := m.root
+	var offset int64
+	buf := make([]byte, 32*1024)
+
+	onHit := func(f *node, pos int) bool {
+		if fn == nil {
+			return true
+		}
+		return fn(Hit{Index: f.index, Pos: pos, Len: int(f.blen)})
+	}
+
+	for {
+		nr, err := r.Read(buf)
+
+		for i := 0; i < nr; i++ {
+			next, ok := m.stepByte(n, buf[i], int(offset)+i, onHit)
+			n = next
+			if !ok {
+				return nil
+			}
+		}
+
+		offset += int64(nr)
+
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package ahocorasick
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestStreamMatchesFindAllAcrossChunkBoundaries(t *testing.T) {
+	dictionary := []string{"she", "he", "her", "hers"}
+	m := NewStringMatcher(dictionary)
+	input := "ushershehishersushers"
+
+	want := m.FindAll([]byte(input))
+
+	chunkSizes := []int{1, 2, 3, 7, len(input)}
+	for _, size := range chunkSizes {
+		var got []Hit
+		s := m.NewStream()
+		s.OnHit = func(index, pos int) bool {
+			got = append(got, Hit{Index: index, Pos: pos})
+			return true
+		}
+
+		for i := 0; i < len(input); i += size {
+			end := i + size
+			if end > len(input) {
+				end = len(input)
+			}
+			if _, err := s.Write([]byte(input[i:end])); err != nil {
+				t.Fatalf("chunk size %d: Write: %v", size, err)
+			}
+		}
+
+		// Stream's OnHit doesn't carry Len, so compare only
+		// Index/Pos against FindAll's result.
+		wantIndexPos := make([]Hit, len(want))
+		for i, h := range want {
+			wantIndexPos[i] = Hit{Index: h.Index, Pos: h.Pos}
+		}
+
+		if !reflect.DeepEqual(got, wantIndexPos) {
+			t.Errorf("chunk size %d: Stream hits = %#v, want %#v", size, got, wantIndexPos)
+		}
+	}
+}
+
+func TestStreamOnHitStopsScanning(t *testing.T) {
+	m := NewStringMatcher([]string{"ab"})
+
+	var hits int
+	s := m.NewStream()
+	s.OnHit = func(index, pos int) bool {
+		hits++
+		return false
+	}
+
+	n, err := s.Write([]byte("ababab"))
+	if err != ErrStopped {
+		t.Fatalf("Write error = %v, want ErrStopped", err)
+	}
+	if n != 2 {
+		t.Errorf("Write consumed %d bytes before stopping, want 2", n)
+	}
+	if hits != 1 {
+		t.Errorf("OnHit called %d times, want exactly 1", hits)
+	}
+
+	if _, err := s.Write([]byte("ab")); err != ErrStopped {
+		t.Errorf("Write after stop = %v, want ErrStopped", err)
+	}
+	if hits != 1 {
+		t.Errorf("OnHit called again after scanning stopped")
+	}
+}
+
+func TestMatchReader(t *testing.T) {
+	dictionary := []string{"she", "he", "her", "hers"}
+	m := NewStringMatcher(dictionary)
+	input := "ushershehishers"
+
+	want := m.FindAll([]byte(input))
+
+	var got []Hit
+	err := m.MatchReader(bytes.NewReader([]byte(input)), func(h Hit) bool {
+		got = append(got, h)
+		return true
+	})
+	if err != nil {
+		t.Fatalf("MatchReader: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MatchReader hits = %#v, want %#v", got, want)
+	}
+}
+
+func TestMatchReaderStopsOnFalse(t *testing.T) {
+	m := NewStringMatcher([]string{"ab"})
+
+	var hits []Hit
+	err := m.MatchReader(bytes.NewReader([]byte("ababab")), func(h Hit) bool {
+		hits = append(hits, h)
+		return false
+	})
+	if err != nil {
+		t.Fatalf("MatchReader: %v", err)
+	}
+	if len(hits) != 1 {
+		t.Errorf("MatchReader delivered %d hits, want exactly 1 before stopping", len(hits))
+	}
+}